@@ -0,0 +1,16 @@
+package common
+
+import (
+	"net/http"
+)
+
+// Healthz serves the router's liveness check.
+type Healthz struct{}
+
+func (h *Healthz) Value() string {
+	return "ok"
+}
+
+func (h *Healthz) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Write([]byte(h.Value()))
+}
@@ -0,0 +1,119 @@
+package config
+
+import (
+	"time"
+)
+
+type StatusConfig struct {
+	Port uint16
+	User string
+	Pass string
+}
+
+type NatsConfig struct {
+	Host string
+	Port uint16
+	User string
+	Pass string
+}
+
+type LoggingConfig struct {
+	File          string
+	Level         string
+	MetronAddress string
+	JobName       string
+}
+
+type OAuthConfig struct {
+	TokenEndpoint string
+	Port          int
+}
+
+// Config holds the router's runtime configuration, populated from the
+// on-disk YAML config and used to wire up the registry, proxy, and NATS
+// connection at startup.
+type Config struct {
+	Port uint16
+	Index uint
+	TraceKey string
+	Ip string
+
+	// Zone is the availability zone this router instance runs in. The
+	// registry compares it with each endpoint's Zone so it can prefer
+	// same-zone endpoints when looking up a route.
+	Zone string
+
+	// ZoneAffinity controls how route.Pool distributes picks across zones.
+	ZoneAffinity ZoneAffinityConfig
+
+	StartResponseDelayInterval           time.Duration
+	PublishStartMessageIntervalInSeconds int
+	PruneStaleDropletsInterval           time.Duration
+	DropletStaleThreshold                time.Duration
+	PublishActiveAppsInterval            time.Duration
+
+	EndpointTimeout time.Duration
+
+	Status  StatusConfig
+	Nats    []NatsConfig
+	Logging LoggingConfig
+	OAuth   OAuthConfig
+
+	Registry RegistryConfig
+
+	// EnableProxyProtocolIngress, when set, makes the frontend listener
+	// expect an optional PROXY protocol (v1 or v2) header ahead of the
+	// HTTP request, and populates RemoteAddr/X-Forwarded-For from it.
+	EnableProxyProtocolIngress bool
+
+	// RequireProxyProtocol rejects connections that don't present a PROXY
+	// header, instead of treating its absence as a plain HTTP connection.
+	RequireProxyProtocol bool
+
+	// PublishRegistryEvents wires up registry.NewNATSEventHook for the
+	// register/unregister/prune lifecycle stages, so external audit and
+	// policy systems can observe registry activity over NATS.
+	PublishRegistryEvents bool
+}
+
+// RegistryConfig selects how route state is kept consistent. Mode "nats"
+// (the default) treats each router's in-memory registry as independently
+// derived from NATS registration traffic. Mode "raft" additionally
+// replicates that state across a cluster of routers via a Raft log, so a
+// NATS partition doesn't cause routers to diverge.
+type RegistryConfig struct {
+	Mode             string
+	RaftPeers        []string
+	DataDir          string
+	HeartbeatTimeout time.Duration
+}
+
+// ZoneAffinityConfig selects how a route.Pool orders endpoints relative to
+// the router's own Zone.
+//
+//   - "prefer" (default): only same-zone endpoints are returned while the
+//     pool has any registered; other zones are used only when it has none.
+//     Endpoint health is not considered, so a failing same-zone endpoint
+//     does not cause a fall back to other zones.
+//   - "strict": only same-zone endpoints are ever returned.
+//   - "spread": endpoints are chosen across all zones in proportion to
+//     Spread's target percentages.
+type ZoneAffinityConfig struct {
+	Mode   string
+	Spread map[string]int
+}
+
+func DefaultConfig() *Config {
+	c := &Config{
+		EndpointTimeout: 60 * time.Second,
+		ZoneAffinity: ZoneAffinityConfig{
+			Mode: "prefer",
+		},
+		Registry: RegistryConfig{
+			Mode:             "nats",
+			HeartbeatTimeout: time.Second,
+		},
+	}
+
+	return c
+}
@@ -0,0 +1,99 @@
+package proxy
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+
+	"github.com/hjinkim-cf1/gorouter/route"
+)
+
+// DialBackend connects to endpoint and, if endpoint.UseProxyProtocol is
+// set, writes the appropriate PROXY protocol header immediately after
+// connect and before any TLS handshake or HTTP bytes, so the backend can
+// recover clientAddr as the original client.
+func DialBackend(endpoint *route.Endpoint, clientAddr net.Addr, dial func(network, address string) (net.Conn, error)) (net.Conn, error) {
+	conn, err := dial("tcp", endpoint.CanonicalAddr())
+	if err != nil {
+		return nil, err
+	}
+
+	if endpoint.UseProxyProtocol == "" {
+		return conn, nil
+	}
+
+	if err := writeProxyHeader(conn, endpoint.UseProxyProtocol, clientAddr, conn.LocalAddr()); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+func writeProxyHeader(conn net.Conn, version string, src, dst net.Addr) error {
+	switch version {
+	case "v1":
+		return writeProxyV1Header(conn, src, dst)
+	case "v2":
+		return writeProxyV2Header(conn, src, dst)
+	default:
+		return fmt.Errorf("proxy: unknown PROXY protocol version %q", version)
+	}
+}
+
+func writeProxyV1Header(conn net.Conn, src, dst net.Addr) error {
+	srcTCP, srcOK := src.(*net.TCPAddr)
+	dstTCP, dstOK := dst.(*net.TCPAddr)
+	if !srcOK || !dstOK {
+		_, err := conn.Write([]byte("PROXY UNKNOWN\r\n"))
+		return err
+	}
+
+	family := "TCP4"
+	if srcTCP.IP.To4() == nil {
+		family = "TCP6"
+	}
+
+	_, err := fmt.Fprintf(conn, "PROXY %s %s %s %d %d\r\n", family, srcTCP.IP, dstTCP.IP, srcTCP.Port, dstTCP.Port)
+	return err
+}
+
+func writeProxyV2Header(conn net.Conn, src, dst net.Addr) error {
+	srcTCP, srcOK := src.(*net.TCPAddr)
+	dstTCP, dstOK := dst.(*net.TCPAddr)
+	if !srcOK || !dstOK {
+		header := append(append([]byte{}, proxyV2Signature...), 0x20, 0x00, 0x00, 0x00)
+		_, err := conn.Write(header)
+		return err
+	}
+
+	isV4 := srcTCP.IP.To4() != nil
+
+	var addrBody []byte
+	family := byte(0x11) // AF_INET, STREAM
+	if isV4 {
+		addrBody = make([]byte, 12)
+		copy(addrBody[0:4], srcTCP.IP.To4())
+		copy(addrBody[4:8], dstTCP.IP.To4())
+		binary.BigEndian.PutUint16(addrBody[8:10], uint16(srcTCP.Port))
+		binary.BigEndian.PutUint16(addrBody[10:12], uint16(dstTCP.Port))
+	} else {
+		family = 0x21 // AF_INET6, STREAM
+		addrBody = make([]byte, 36)
+		copy(addrBody[0:16], srcTCP.IP.To16())
+		copy(addrBody[16:32], dstTCP.IP.To16())
+		binary.BigEndian.PutUint16(addrBody[32:34], uint16(srcTCP.Port))
+		binary.BigEndian.PutUint16(addrBody[34:36], uint16(dstTCP.Port))
+	}
+
+	header := make([]byte, 0, len(proxyV2Signature)+4+len(addrBody))
+	header = append(header, proxyV2Signature...)
+	header = append(header, 0x21, family) // version 2, command PROXY
+	lenBuf := make([]byte, 2)
+	binary.BigEndian.PutUint16(lenBuf, uint16(len(addrBody)))
+	header = append(header, lenBuf...)
+	header = append(header, addrBody...)
+
+	_, err := conn.Write(header)
+	return err
+}
@@ -0,0 +1,57 @@
+package proxy
+
+import (
+	"net/http"
+
+	"github.com/hjinkim-cf1/gorouter/registry"
+	"github.com/hjinkim-cf1/gorouter/route"
+)
+
+// EndpointIterator hands a backend pool's endpoints to the proxy one at a
+// time, deferring the actual selection policy (zone affinity, per-URI load
+// balancing algorithm, ...) to the pool and its LoadBalancer.
+type EndpointIterator struct {
+	pool       *route.Pool
+	lb         registry.LoadBalancer
+	remoteAddr string
+}
+
+func NewEndpointIterator(pool *route.Pool, lb registry.LoadBalancer, req *http.Request) *EndpointIterator {
+	return &EndpointIterator{
+		pool:       pool,
+		lb:         lb,
+		remoteAddr: clientAddr(req),
+	}
+}
+
+// Next returns the next endpoint to try for this request, or nil if the
+// pool has nothing registered.
+func (it *EndpointIterator) Next() *route.Endpoint {
+	if it.pool == nil || it.lb == nil {
+		return nil
+	}
+
+	return it.lb.Next(it.pool, it.remoteAddr)
+}
+
+// Dispatch runs fn against the next endpoint, tracking in-flight
+// connections and reporting the outcome back to the load balancer.
+func (it *EndpointIterator) Dispatch(fn func(endpoint *route.Endpoint) error) error {
+	endpoint := it.Next()
+	if endpoint == nil {
+		return nil
+	}
+
+	var err error
+	withConnectionTracking(endpoint, func() {
+		err = fn(endpoint)
+	})
+
+	result := registry.Success
+	if err != nil {
+		result = registry.Failure
+	}
+	it.lb.Notify(endpoint, result)
+
+	return err
+}
@@ -0,0 +1,41 @@
+package proxy
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/hjinkim-cf1/gorouter/route"
+)
+
+// clientAddr resolves the address the ip_hash load-balancing algorithm
+// should hash on: the host of the first hop's RemoteAddr, falling back to
+// the leftmost X-Forwarded-For entry only when RemoteAddr has no host.
+func clientAddr(req *http.Request) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+
+	if host != "" {
+		return host
+	}
+
+	if xff := req.Header.Get("X-Forwarded-For"); xff != "" {
+		if first := strings.TrimSpace(strings.Split(xff, ",")[0]); first != "" {
+			return first
+		}
+	}
+
+	return host
+}
+
+// withConnectionTracking increments endpoint's in-flight counter for the
+// duration of fn, so the least_connections algorithm sees an accurate
+// count. fn performs the actual backend round trip.
+func withConnectionTracking(endpoint *route.Endpoint, fn func()) {
+	endpoint.IncrementConnections()
+	defer endpoint.DecrementConnections()
+
+	fn()
+}
@@ -0,0 +1,31 @@
+package proxy
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/hjinkim-cf1/gorouter/registry"
+	"github.com/hjinkim-cf1/gorouter/route"
+)
+
+// ErrNoTaggedEndpoint is returned when a tag rule matched the request but
+// required a set of tags no registered endpoint satisfies, and the rule's
+// Fallback is "none". The caller should respond 503.
+var ErrNoTaggedEndpoint = errors.New("no endpoint satisfies the matched tag rule")
+
+// ResolveIterator looks up uri's pool, narrows it by the registry's tag
+// router, and returns an EndpointIterator over whatever's left using the
+// route's configured load-balancing algorithm.
+func ResolveIterator(reg *registry.RouteRegistry, uri route.Uri, req *http.Request) (*EndpointIterator, error) {
+	pool := reg.Lookup(uri)
+	if pool == nil {
+		return nil, nil
+	}
+
+	tagged, ok := reg.TagRouter().Resolve(req, pool)
+	if !ok {
+		return nil, ErrNoTaggedEndpoint
+	}
+
+	return NewEndpointIterator(tagged, reg.LoadBalancer(uri), req), nil
+}
@@ -0,0 +1,199 @@
+package proxy
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ErrMissingProxyHeader is returned by ProxyProtocolListener.Accept when
+// RequireProxyProtocol is set and a connection doesn't present a PROXY
+// header.
+var ErrMissingProxyHeader = errors.New("proxy: connection is missing a required PROXY protocol header")
+
+var proxyV2Signature = []byte{0x0D, 0x0A, 0x0D, 0x0A, 0x00, 0x0D, 0x0A, 0x51, 0x55, 0x49, 0x54, 0x0A}
+
+// ProxyProtocolListener wraps a net.Listener so that each accepted
+// connection is checked for an optional PROXY protocol v1 (text) or v2
+// (binary) header before the HTTP parser ever sees the bytes. When a
+// header is present, the returned conn's RemoteAddr reflects the original
+// client rather than the immediate peer (e.g. a load balancer).
+type ProxyProtocolListener struct {
+	net.Listener
+
+	// Require rejects connections that don't present a PROXY header,
+	// rather than falling back to treating them as plain HTTP.
+	Require bool
+}
+
+func NewProxyProtocolListener(inner net.Listener, require bool) *ProxyProtocolListener {
+	return &ProxyProtocolListener{Listener: inner, Require: require}
+}
+
+// bufReaderPool recycles the bufio.Readers Accept uses to peek for a PROXY
+// header, so no connection allocates a fresh 256-byte buffer. A non-PROXY
+// connection whose peeked bytes are still buffered still allocates its
+// bufferedConn wrapper.
+var bufReaderPool = sync.Pool{
+	New: func() interface{} { return bufio.NewReaderSize(nil, 256) },
+}
+
+func getBufReader(r net.Conn) *bufio.Reader {
+	br := bufReaderPool.Get().(*bufio.Reader)
+	br.Reset(r)
+	return br
+}
+
+func putBufReader(br *bufio.Reader) {
+	br.Reset(nil)
+	bufReaderPool.Put(br)
+}
+
+func (l *ProxyProtocolListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	br := getBufReader(conn)
+
+	remoteAddr, err := readProxyHeader(br)
+	if err != nil {
+		conn.Close()
+		putBufReader(br)
+		return nil, err
+	}
+
+	if remoteAddr == nil {
+		if l.Require {
+			conn.Close()
+			putBufReader(br)
+			return nil, ErrMissingProxyHeader
+		}
+		// No PROXY header: the non-PROXY fast path. If bufio holds no
+		// bytes at all (peeked bytes count as buffered), avoid wrapping
+		// so callers pay nothing extra to read the connection.
+		if br.Buffered() == 0 {
+			putBufReader(br)
+			return conn, nil
+		}
+		return &bufferedConn{Conn: conn, r: br}, nil
+	}
+
+	return &proxyProtoConn{Conn: &bufferedConn{Conn: conn, r: br}, remoteAddr: remoteAddr}, nil
+}
+
+// readProxyHeader peeks at br's first bytes to detect and, if present,
+// consume a PROXY protocol v1 or v2 header, returning the original client
+// address it describes. A nil address with a nil error means either no
+// header was present, or a v2 header was consumed that carries no address
+// (AF_UNSPEC).
+func readProxyHeader(br *bufio.Reader) (net.Addr, error) {
+	sig, err := br.Peek(len(proxyV2Signature))
+	if err == nil && string(sig) == string(proxyV2Signature) {
+		return readProxyV2Header(br)
+	}
+
+	prefix, err := br.Peek(6)
+	if err != nil || string(prefix) != "PROXY " {
+		return nil, nil
+	}
+
+	return readProxyV1Header(br)
+}
+
+func readProxyV1Header(br *bufio.Reader) (net.Addr, error) {
+	line, err := br.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("proxy: malformed v1 header: %s", err)
+	}
+
+	fields := strings.Fields(strings.TrimSpace(line))
+	// "PROXY" protocol srcIP dstIP srcPort dstPort
+	if len(fields) < 6 || fields[0] != "PROXY" {
+		return nil, fmt.Errorf("proxy: malformed v1 header: %q", line)
+	}
+
+	port, err := strconv.Atoi(fields[4])
+	if err != nil {
+		return nil, fmt.Errorf("proxy: malformed v1 header port: %s", err)
+	}
+
+	return &net.TCPAddr{IP: net.ParseIP(fields[2]), Port: port}, nil
+}
+
+func readProxyV2Header(br *bufio.Reader) (net.Addr, error) {
+	header := make([]byte, 16)
+	if _, err := readFull(br, header[:len(proxyV2Signature)+4]); err != nil {
+		return nil, fmt.Errorf("proxy: malformed v2 header: %s", err)
+	}
+
+	addrLen := binary.BigEndian.Uint16(header[len(proxyV2Signature)+2 : len(proxyV2Signature)+4])
+	family := header[len(proxyV2Signature)+1]
+
+	body := make([]byte, addrLen)
+	if _, err := readFull(br, body); err != nil {
+		return nil, fmt.Errorf("proxy: malformed v2 header body: %s", err)
+	}
+
+	switch family >> 4 {
+	case 0x1: // AF_INET
+		if len(body) < 12 {
+			return nil, fmt.Errorf("proxy: v2 ipv4 body too short")
+		}
+		return &net.TCPAddr{IP: net.IP(body[0:4]), Port: int(binary.BigEndian.Uint16(body[8:10]))}, nil
+	case 0x2: // AF_INET6
+		if len(body) < 36 {
+			return nil, fmt.Errorf("proxy: v2 ipv6 body too short")
+		}
+		return &net.TCPAddr{IP: net.IP(body[0:16]), Port: int(binary.BigEndian.Uint16(body[32:34]))}, nil
+	default: // AF_UNSPEC (health checks, etc.) - no address to report
+		return nil, nil
+	}
+}
+
+func readFull(br *bufio.Reader, buf []byte) (int, error) {
+	n := 0
+	for n < len(buf) {
+		m, err := br.Read(buf[n:])
+		n += m
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// bufferedConn serves any bytes bufio.Reader already pulled off the wire
+// while peeking for a PROXY header before falling through to the raw conn.
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
+
+// Close returns c's bufio.Reader to bufReaderPool once the underlying conn
+// is done with it, so the allocation Accept avoided on the fast path isn't
+// simply paid back by every wrapped connection leaking its own reader.
+func (c *bufferedConn) Close() error {
+	err := c.Conn.Close()
+	putBufReader(c.r)
+	return err
+}
+
+type proxyProtoConn struct {
+	net.Conn
+	remoteAddr net.Addr
+}
+
+func (c *proxyProtoConn) RemoteAddr() net.Addr {
+	return c.remoteAddr
+}
@@ -0,0 +1,158 @@
+package proxy
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+)
+
+func TestReadProxyHeaderV1(t *testing.T) {
+	br := bufio.NewReader(strings.NewReader("PROXY TCP4 192.0.2.1 192.0.2.2 56324 443\r\nGET / HTTP/1.1\r\n\r\n"))
+
+	addr, err := readProxyHeader(br)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	tcpAddr, ok := addr.(*net.TCPAddr)
+	if !ok {
+		t.Fatalf("expected *net.TCPAddr, got %T", addr)
+	}
+	if tcpAddr.IP.String() != "192.0.2.1" || tcpAddr.Port != 56324 {
+		t.Fatalf("got %s:%d, want 192.0.2.1:56324", tcpAddr.IP, tcpAddr.Port)
+	}
+
+	rest, _ := br.ReadString('\n')
+	if rest != "GET / HTTP/1.1\r\n" {
+		t.Fatalf("header wasn't fully consumed, rest starts with %q", rest)
+	}
+}
+
+func TestReadProxyHeaderV1Malformed(t *testing.T) {
+	br := bufio.NewReader(strings.NewReader("PROXY TCP4 192.0.2.1\r\n"))
+
+	if _, err := readProxyHeader(br); err == nil {
+		t.Fatal("expected an error for a truncated v1 header")
+	}
+}
+
+func TestReadProxyHeaderV2(t *testing.T) {
+	header := append([]byte{}, proxyV2Signature...)
+	header = append(header, 0x21, 0x11, 0x00, 0x0C) // ver/cmd, AF_INET/STREAM, addrLen=12
+	header = append(header, 192, 0, 2, 1)           // src addr
+	header = append(header, 192, 0, 2, 2)           // dst addr
+	header = append(header, 0xDC, 0x04)             // src port 56324
+	header = append(header, 0x01, 0xBB)             // dst port 443
+
+	br := bufio.NewReader(strings.NewReader(string(header) + "GET / HTTP/1.1\r\n\r\n"))
+
+	addr, err := readProxyHeader(br)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	tcpAddr, ok := addr.(*net.TCPAddr)
+	if !ok {
+		t.Fatalf("expected *net.TCPAddr, got %T", addr)
+	}
+	if tcpAddr.IP.String() != "192.0.2.1" || tcpAddr.Port != 56324 {
+		t.Fatalf("got %s:%d, want 192.0.2.1:56324", tcpAddr.IP, tcpAddr.Port)
+	}
+
+	rest, _ := br.ReadString('\n')
+	if rest != "GET / HTTP/1.1\r\n" {
+		t.Fatalf("header wasn't fully consumed, rest starts with %q", rest)
+	}
+}
+
+func TestReadProxyHeaderNoHeader(t *testing.T) {
+	br := bufio.NewReader(strings.NewReader("GET / HTTP/1.1\r\nHost: example.com\r\n\r\n"))
+
+	addr, err := readProxyHeader(br)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if addr != nil {
+		t.Fatalf("expected nil addr for a non-PROXY connection, got %v", addr)
+	}
+}
+
+func TestProxyProtocolListenerAccept(t *testing.T) {
+	fl := &pipeListener{conns: make(chan net.Conn, 1)}
+	l := NewProxyProtocolListener(fl, false)
+
+	client, server := net.Pipe()
+	defer client.Close()
+	fl.conns <- server
+
+	go client.Write([]byte("PROXY TCP4 192.0.2.1 192.0.2.2 56324 443\r\n"))
+
+	conn, err := l.Accept()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer conn.Close()
+
+	tcpAddr, ok := conn.RemoteAddr().(*net.TCPAddr)
+	if !ok {
+		t.Fatalf("expected *net.TCPAddr, got %T", conn.RemoteAddr())
+	}
+	if tcpAddr.IP.String() != "192.0.2.1" || tcpAddr.Port != 56324 {
+		t.Fatalf("got %s:%d, want 192.0.2.1:56324", tcpAddr.IP, tcpAddr.Port)
+	}
+}
+
+func TestProxyProtocolListenerAcceptRequiresHeader(t *testing.T) {
+	fl := &pipeListener{conns: make(chan net.Conn, 1)}
+	l := NewProxyProtocolListener(fl, true)
+
+	client, server := net.Pipe()
+	defer client.Close()
+	fl.conns <- server
+
+	go client.Write([]byte("GET / HTTP/1.1\r\n\r\n"))
+
+	_, err := l.Accept()
+	if err != ErrMissingProxyHeader {
+		t.Fatalf("got err %v, want ErrMissingProxyHeader", err)
+	}
+}
+
+func TestProxyProtocolListenerAcceptPassesThroughPlainConns(t *testing.T) {
+	fl := &pipeListener{conns: make(chan net.Conn, 1)}
+	l := NewProxyProtocolListener(fl, false)
+
+	client, server := net.Pipe()
+	defer client.Close()
+	fl.conns <- server
+
+	const request = "GET / HTTP/1.1\r\nHost: example.com\r\n\r\n"
+	go client.Write([]byte(request))
+
+	conn, err := l.Accept()
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	defer conn.Close()
+
+	buf := make([]byte, len(request))
+	if _, err := readFullConn(conn, buf); err != nil {
+		t.Fatalf("unexpected error reading through conn: %s", err)
+	}
+	if string(buf) != request {
+		t.Fatalf("got %q, want %q", buf, request)
+	}
+}
+
+func readFullConn(conn net.Conn, buf []byte) (int, error) {
+	n := 0
+	for n < len(buf) {
+		m, err := conn.Read(buf[n:])
+		n += m
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
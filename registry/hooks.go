@@ -0,0 +1,102 @@
+package registry
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/cloudfoundry/yagnats"
+
+	"github.com/hjinkim-cf1/gorouter/route"
+)
+
+// Lifecycle stages a HookFunc can be registered for.
+const (
+	HookRegister   = "register"
+	HookUnregister = "unregister"
+	HookPrune      = "prune"
+	HookStartup    = "startup"
+	HookShutdown   = "shutdown"
+)
+
+// HookFunc is called for every RouteRegistry lifecycle event of the stage
+// it was registered for. uri and endpoint are immutable snapshots; for the
+// startup/shutdown stages, which aren't about a particular route, both are
+// zero values. Returning a non-nil error from a HookRegister hook rejects
+// the registration (e.g. to block routes to unapproved hosts, or enforce a
+// max endpoints-per-URI quota). Errors from unregister and prune hooks are
+// only logged; NotifyStartup and NotifyShutdown return theirs to the caller.
+type HookFunc func(uri route.Uri, endpoint *route.Endpoint) error
+
+type hookRegistry struct {
+	sync.RWMutex
+	byStage map[string][]HookFunc
+}
+
+func newHookRegistry() *hookRegistry {
+	return &hookRegistry{byStage: make(map[string][]HookFunc)}
+}
+
+func (h *hookRegistry) register(stage string, fn HookFunc) {
+	h.Lock()
+	h.byStage[stage] = append(h.byStage[stage], fn)
+	h.Unlock()
+}
+
+// run calls every hook registered for stage in order, stopping at (and
+// returning) the first error.
+func (h *hookRegistry) run(stage string, uri route.Uri, endpoint *route.Endpoint) error {
+	h.RLock()
+	fns := h.byStage[stage]
+	h.RUnlock()
+
+	for _, fn := range fns {
+		if err := fn(uri, endpoint); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// RegisterHook attaches fn to run whenever a RouteRegistry event of the
+// given stage ("register", "unregister", "prune", "startup", "shutdown")
+// occurs. Hooks for the same stage run in the order they were registered.
+func (r *RouteRegistry) RegisterHook(stage string, fn HookFunc) {
+	r.hooks.register(stage, fn)
+}
+
+// NotifyStartup and NotifyShutdown let the process entrypoint fire the
+// stages that aren't tied to a particular route.
+func (r *RouteRegistry) NotifyStartup() error {
+	return r.hooks.run(HookStartup, "", nil)
+}
+
+func (r *RouteRegistry) NotifyShutdown() error {
+	return r.hooks.run(HookShutdown, "", nil)
+}
+
+// natsEventPayload is what NewNATSEventHook publishes.
+type natsEventPayload struct {
+	Stage    string          `json:"stage"`
+	Uri      route.Uri       `json:"uri,omitempty"`
+	Endpoint *route.Endpoint `json:"endpoint,omitempty"`
+}
+
+// NewNATSEventHook returns a HookFunc that publishes a JSON event on
+// "router.registry.events.<stage>" so audit and policy systems can observe
+// registry activity without linking against gorouter. It never rejects a
+// registration - failures to publish are swallowed, since losing an audit
+// event shouldn't take a route down.
+func NewNATSEventHook(mbus yagnats.NATSConn, stage string) HookFunc {
+	subject := "router.registry.events." + stage
+
+	return func(uri route.Uri, endpoint *route.Endpoint) error {
+		payload, err := json.Marshal(natsEventPayload{Stage: stage, Uri: uri, Endpoint: endpoint})
+		if err != nil {
+			return nil
+		}
+
+		mbus.Publish(subject, payload)
+		return nil
+	}
+}
@@ -0,0 +1,110 @@
+package registry_test
+
+import (
+	"errors"
+
+	"github.com/hjinkim-cf1/gorouter/config"
+	. "github.com/hjinkim-cf1/gorouter/registry"
+	"github.com/hjinkim-cf1/gorouter/route"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RouteRegistry hooks", func() {
+	var (
+		c        *config.Config
+		registry *RouteRegistry
+	)
+
+	BeforeEach(func() {
+		c = config.DefaultConfig()
+		registry = NewRouteRegistry(c, nil)
+	})
+
+	It("rejects a registration when a register hook errors", func() {
+		registry.RegisterHook(HookRegister, func(uri route.Uri, endpoint *route.Endpoint) error {
+			return errors.New("rejected")
+		})
+
+		endpoint := route.NewEndpoint("app", "10.0.0.1", 1234, "a", "", nil)
+		err := registry.Register("foo.com", endpoint)
+
+		Ω(err).Should(HaveOccurred())
+		Ω(registry.Lookup("foo.com")).Should(BeNil())
+	})
+
+	It("admits a registration when no register hook rejects it", func() {
+		var seenURI route.Uri
+		registry.RegisterHook(HookRegister, func(uri route.Uri, endpoint *route.Endpoint) error {
+			seenURI = uri
+			return nil
+		})
+
+		endpoint := route.NewEndpoint("app", "10.0.0.1", 1234, "a", "", nil)
+		Ω(registry.Register("foo.com", endpoint)).Should(Succeed())
+
+		Ω(seenURI).Should(Equal(route.Uri("foo.com")))
+		Ω(registry.Lookup("foo.com")).ShouldNot(BeNil())
+	})
+
+	It("runs hooks for the same stage in registration order and stops at the first error", func() {
+		var order []int
+
+		registry.RegisterHook(HookRegister, func(uri route.Uri, endpoint *route.Endpoint) error {
+			order = append(order, 1)
+			return nil
+		})
+		registry.RegisterHook(HookRegister, func(uri route.Uri, endpoint *route.Endpoint) error {
+			order = append(order, 2)
+			return errors.New("rejected by second hook")
+		})
+		registry.RegisterHook(HookRegister, func(uri route.Uri, endpoint *route.Endpoint) error {
+			order = append(order, 3)
+			return nil
+		})
+
+		endpoint := route.NewEndpoint("app", "10.0.0.1", 1234, "a", "", nil)
+		err := registry.Register("foo.com", endpoint)
+
+		Ω(err).Should(HaveOccurred())
+		Ω(order).Should(Equal([]int{1, 2}))
+	})
+
+	It("only runs unregister hooks when an endpoint was actually removed", func() {
+		calls := 0
+		registry.RegisterHook(HookUnregister, func(uri route.Uri, endpoint *route.Endpoint) error {
+			calls++
+			return nil
+		})
+
+		endpoint := route.NewEndpoint("app", "10.0.0.1", 1234, "a", "", nil)
+		registry.Unregister("foo.com", endpoint)
+		Ω(calls).Should(Equal(0))
+
+		Ω(registry.Register("foo.com", endpoint)).Should(Succeed())
+		registry.Unregister("foo.com", endpoint)
+		Ω(calls).Should(Equal(1))
+	})
+
+	It("runs startup and shutdown hooks on demand", func() {
+		startupCalled := false
+		shutdownCalled := false
+
+		registry.RegisterHook(HookStartup, func(uri route.Uri, endpoint *route.Endpoint) error {
+			startupCalled = true
+			return nil
+		})
+		registry.RegisterHook(HookShutdown, func(uri route.Uri, endpoint *route.Endpoint) error {
+			shutdownCalled = true
+			return nil
+		})
+
+		Ω(registry.NotifyStartup()).Should(Succeed())
+		Ω(startupCalled).Should(BeTrue())
+		Ω(shutdownCalled).Should(BeFalse())
+
+		Ω(registry.NotifyShutdown()).Should(Succeed())
+		Ω(shutdownCalled).Should(BeTrue())
+	})
+})
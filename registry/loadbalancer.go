@@ -0,0 +1,231 @@
+package registry
+
+import (
+	"hash/fnv"
+	"sync"
+
+	"github.com/hjinkim-cf1/gorouter/route"
+)
+
+// Result is the outcome of a request dispatched to an endpoint chosen by a
+// LoadBalancer, reported back via Notify so algorithms (and their stats)
+// can react to failures.
+type Result int
+
+const (
+	Success Result = iota
+	Failure
+)
+
+// LoadBalancer picks an endpoint out of a route.Pool for each request and
+// is notified of the outcome once the backend has responded. Implementations
+// must be safe for concurrent use.
+type LoadBalancer interface {
+	Algorithm() string
+	Next(pool *route.Pool, remoteAddr string) *route.Endpoint
+	Notify(endpoint *route.Endpoint, result Result)
+}
+
+// lbStats is embedded in every LoadBalancer implementation to provide the
+// Prometheus-style success/failure counters surfaced via varz.
+type lbStats struct {
+	sync.Mutex
+	successes uint64
+	failures  uint64
+}
+
+func (s *lbStats) record(result Result) {
+	s.Lock()
+	if result == Success {
+		s.successes++
+	} else {
+		s.failures++
+	}
+	s.Unlock()
+}
+
+func (s *lbStats) snapshot() (successes, failures uint64) {
+	s.Lock()
+	defer s.Unlock()
+	return s.successes, s.failures
+}
+
+// NewLoadBalancer returns the LoadBalancer implementation for the given
+// algorithm name, defaulting to round-robin for an unrecognized or empty
+// name.
+func NewLoadBalancer(algorithm string) LoadBalancer {
+	switch algorithm {
+	case "least_connections":
+		return &leastConnectionsBalancer{}
+	case "ip_hash":
+		return &ipHashBalancer{}
+	case "weighted_round_robin":
+		return &weightedRoundRobinBalancer{}
+	default:
+		return &roundRobinBalancer{}
+	}
+}
+
+// roundRobinBalancer defers to route.Pool's own rotation (which is already
+// zone-affine), so it adds nothing beyond bookkeeping stats.
+type roundRobinBalancer struct {
+	lbStats
+}
+
+func (b *roundRobinBalancer) Algorithm() string { return "round_robin" }
+
+func (b *roundRobinBalancer) Next(pool *route.Pool, remoteAddr string) *route.Endpoint {
+	return pool.Next()
+}
+
+func (b *roundRobinBalancer) Notify(endpoint *route.Endpoint, result Result) {
+	b.record(result)
+}
+
+// leastConnectionsBalancer picks the endpoint with the fewest in-flight
+// requests, as tracked by route.Endpoint's connection counter.
+type leastConnectionsBalancer struct {
+	lbStats
+}
+
+func (b *leastConnectionsBalancer) Algorithm() string { return "least_connections" }
+
+func (b *leastConnectionsBalancer) Next(pool *route.Pool, remoteAddr string) *route.Endpoint {
+	var best *route.Endpoint
+
+	for _, endpoint := range pool.EligibleEndpoints() {
+		if best == nil || endpoint.ConnectionCount() < best.ConnectionCount() {
+			best = endpoint
+		}
+	}
+
+	return best
+}
+
+func (b *leastConnectionsBalancer) Notify(endpoint *route.Endpoint, result Result) {
+	b.record(result)
+}
+
+// ipHashBalancer sticks a client to the same endpoint for as long as
+// possible using rendezvous (highest random weight) hashing, so adding or
+// removing a single endpoint only reshuffles that endpoint's share of
+// traffic instead of the whole pool.
+type ipHashBalancer struct {
+	lbStats
+}
+
+func (b *ipHashBalancer) Algorithm() string { return "ip_hash" }
+
+func (b *ipHashBalancer) Next(pool *route.Pool, remoteAddr string) *route.Endpoint {
+	var best *route.Endpoint
+	var bestScore uint64
+
+	for _, endpoint := range pool.EligibleEndpoints() {
+		score := rendezvousScore(remoteAddr, endpoint.CanonicalAddr())
+		if best == nil || score > bestScore {
+			best = endpoint
+			bestScore = score
+		}
+	}
+
+	return best
+}
+
+func (b *ipHashBalancer) Notify(endpoint *route.Endpoint, result Result) {
+	b.record(result)
+}
+
+func rendezvousScore(key, candidate string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	h.Write([]byte{0})
+	h.Write([]byte(candidate))
+	return h.Sum64()
+}
+
+// weightedRoundRobinBalancer implements smooth weighted round-robin:
+// each endpoint accrues its Weight every pick and the one with the
+// highest running total is chosen and discounted by the sum of all
+// weights, so high-weight endpoints are picked more often without ever
+// starving low-weight ones in a burst.
+type weightedRoundRobinBalancer struct {
+	lbStats
+
+	mu      sync.Mutex
+	current map[string]int
+}
+
+func (b *weightedRoundRobinBalancer) Algorithm() string { return "weighted_round_robin" }
+
+func (b *weightedRoundRobinBalancer) Next(pool *route.Pool, remoteAddr string) *route.Endpoint {
+	type candidate struct {
+		endpoint *route.Endpoint
+		weight   int
+	}
+
+	var candidates []candidate
+	total := 0
+
+	for _, endpoint := range pool.EligibleEndpoints() {
+		weight := endpoint.Weight
+		if weight <= 0 {
+			weight = 1
+		}
+		candidates = append(candidates, candidate{endpoint: endpoint, weight: weight})
+		total += weight
+	}
+
+	if len(candidates) == 0 {
+		return nil
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.current == nil {
+		b.current = make(map[string]int)
+	}
+
+	var best *candidate
+	for i := range candidates {
+		c := &candidates[i]
+		addr := c.endpoint.CanonicalAddr()
+		b.current[addr] += c.weight
+		if best == nil || b.current[addr] > b.current[best.endpoint.CanonicalAddr()] {
+			best = c
+		}
+	}
+
+	b.current[best.endpoint.CanonicalAddr()] -= total
+
+	return best.endpoint
+}
+
+func (b *weightedRoundRobinBalancer) Notify(endpoint *route.Endpoint, result Result) {
+	b.record(result)
+}
+
+// LoadBalancerVarz is the per-URI breakdown surfaced at /varz: which
+// algorithm is in effect and how many requests it has routed.
+type LoadBalancerVarz struct {
+	Algorithm string `json:"algorithm"`
+	Successes uint64 `json:"successes"`
+	Failures  uint64 `json:"failures"`
+}
+
+func varzFor(lb LoadBalancer) LoadBalancerVarz {
+	v := LoadBalancerVarz{Algorithm: lb.Algorithm()}
+
+	switch b := lb.(type) {
+	case *roundRobinBalancer:
+		v.Successes, v.Failures = b.snapshot()
+	case *leastConnectionsBalancer:
+		v.Successes, v.Failures = b.snapshot()
+	case *ipHashBalancer:
+		v.Successes, v.Failures = b.snapshot()
+	case *weightedRoundRobinBalancer:
+		v.Successes, v.Failures = b.snapshot()
+	}
+
+	return v
+}
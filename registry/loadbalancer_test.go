@@ -0,0 +1,148 @@
+package registry_test
+
+import (
+	"time"
+
+	. "github.com/hjinkim-cf1/gorouter/registry"
+	"github.com/hjinkim-cf1/gorouter/route"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NewLoadBalancer", func() {
+	It("defaults unrecognized and empty algorithm names to round_robin", func() {
+		Ω(NewLoadBalancer("bogus").Algorithm()).Should(Equal("round_robin"))
+		Ω(NewLoadBalancer("").Algorithm()).Should(Equal("round_robin"))
+	})
+
+	Describe("round_robin", func() {
+		It("cycles through the pool in order", func() {
+			pool := route.NewPool(time.Minute, "", "", nil)
+			a := route.NewEndpoint("app", "10.0.0.1", 1, "a", "", nil)
+			b := route.NewEndpoint("app", "10.0.0.2", 2, "b", "", nil)
+			pool.Put(a)
+			pool.Put(b)
+
+			lb := NewLoadBalancer("round_robin")
+			Ω(lb.Next(pool, "")).Should(Equal(a))
+			Ω(lb.Next(pool, "")).Should(Equal(b))
+			Ω(lb.Next(pool, "")).Should(Equal(a))
+		})
+	})
+
+	Describe("least_connections", func() {
+		It("picks the endpoint with the fewest in-flight connections", func() {
+			pool := route.NewPool(time.Minute, "", "", nil)
+			busy := route.NewEndpoint("app", "10.0.0.1", 1, "busy", "", nil)
+			idle := route.NewEndpoint("app", "10.0.0.2", 2, "idle", "", nil)
+			pool.Put(busy)
+			pool.Put(idle)
+
+			busy.IncrementConnections()
+			busy.IncrementConnections()
+			idle.IncrementConnections()
+
+			lb := NewLoadBalancer("least_connections")
+			Ω(lb.Next(pool, "")).Should(Equal(idle))
+		})
+	})
+
+	Describe("ip_hash", func() {
+		It("sticks the same remoteAddr to the same endpoint", func() {
+			pool := route.NewPool(time.Minute, "", "", nil)
+			pool.Put(route.NewEndpoint("app", "10.0.0.1", 1, "a", "", nil))
+			pool.Put(route.NewEndpoint("app", "10.0.0.2", 2, "b", "", nil))
+			pool.Put(route.NewEndpoint("app", "10.0.0.3", 3, "c", "", nil))
+
+			lb := NewLoadBalancer("ip_hash")
+
+			first := lb.Next(pool, "203.0.113.7")
+			for i := 0; i < 10; i++ {
+				Ω(lb.Next(pool, "203.0.113.7")).Should(Equal(first))
+			}
+		})
+
+		It("can choose differently for a different remoteAddr", func() {
+			pool := route.NewPool(time.Minute, "", "", nil)
+			pool.Put(route.NewEndpoint("app", "10.0.0.1", 1, "a", "", nil))
+			pool.Put(route.NewEndpoint("app", "10.0.0.2", 2, "b", "", nil))
+			pool.Put(route.NewEndpoint("app", "10.0.0.3", 3, "c", "", nil))
+
+			lb := NewLoadBalancer("ip_hash")
+
+			seen := map[string]bool{}
+			for _, addr := range []string{"203.0.113.1", "203.0.113.2", "203.0.113.3", "203.0.113.4"} {
+				seen[lb.Next(pool, addr).PrivateInstanceId] = true
+			}
+
+			Ω(len(seen)).Should(BeNumerically(">", 1))
+		})
+	})
+
+	Describe("zone affinity", func() {
+		It("restricts least_connections to the local zone in strict mode", func() {
+			pool := route.NewPool(time.Minute, "z1", "strict", nil)
+			local := route.NewEndpoint("app", "10.0.0.1", 1, "local", "z1", nil)
+			remote := route.NewEndpoint("app", "10.0.0.2", 2, "remote", "z2", nil)
+			pool.Put(local)
+			pool.Put(remote)
+
+			remote.IncrementConnections()
+			remote.IncrementConnections()
+			local.IncrementConnections()
+
+			lb := NewLoadBalancer("least_connections")
+			Ω(lb.Next(pool, "")).Should(Equal(local))
+		})
+
+		It("restricts ip_hash to the local zone in strict mode", func() {
+			pool := route.NewPool(time.Minute, "z1", "strict", nil)
+			pool.Put(route.NewEndpoint("app", "10.0.0.1", 1, "local", "z1", nil))
+			remote := route.NewEndpoint("app", "10.0.0.2", 2, "remote", "z2", nil)
+			pool.Put(remote)
+
+			lb := NewLoadBalancer("ip_hash")
+			for i := 0; i < 10; i++ {
+				Ω(lb.Next(pool, "203.0.113.7").PrivateInstanceId).ShouldNot(Equal(remote.PrivateInstanceId))
+			}
+		})
+
+		It("restricts weighted_round_robin to the local zone in strict mode", func() {
+			pool := route.NewPool(time.Minute, "z1", "strict", nil)
+			local := route.NewEndpoint("app", "10.0.0.1", 1, "local", "z1", nil)
+			remote := route.NewEndpoint("app", "10.0.0.2", 2, "remote", "z2", nil)
+			remote.Weight = 10
+			pool.Put(local)
+			pool.Put(remote)
+
+			lb := NewLoadBalancer("weighted_round_robin")
+			for i := 0; i < 10; i++ {
+				Ω(lb.Next(pool, "")).Should(Equal(local))
+			}
+		})
+	})
+
+	Describe("weighted_round_robin", func() {
+		It("picks higher-weighted endpoints proportionally more often", func() {
+			pool := route.NewPool(time.Minute, "", "", nil)
+			heavy := route.NewEndpoint("app", "10.0.0.1", 1, "heavy", "", nil)
+			heavy.Weight = 3
+			light := route.NewEndpoint("app", "10.0.0.2", 2, "light", "", nil)
+			light.Weight = 1
+			pool.Put(heavy)
+			pool.Put(light)
+
+			lb := NewLoadBalancer("weighted_round_robin")
+
+			counts := map[string]int{}
+			const picks = 400
+			for i := 0; i < picks; i++ {
+				counts[lb.Next(pool, "").PrivateInstanceId]++
+			}
+
+			heavyPct := float64(counts["heavy"]) / float64(picks) * 100
+			Ω(heavyPct).Should(BeNumerically("~", 75, 2))
+		})
+	})
+})
@@ -0,0 +1,103 @@
+package registry
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/hashicorp/raft"
+
+	"github.com/hjinkim-cf1/gorouter/route"
+)
+
+// command is the Raft log entry applied to every node's fsm. It mirrors
+// the two mutating RegistryInterface calls; everything else (Lookup,
+// NumUris, ...) is a local read against the state the log has already
+// produced.
+type command struct {
+	Op       string          `json:"op"` // "register" or "unregister"
+	Uri      route.Uri       `json:"uri"`
+	Endpoint *route.Endpoint `json:"endpoint"`
+}
+
+// fsm applies committed Raft log entries to a plain *RouteRegistry. It is
+// applied independently on every node in the cluster (leader and
+// followers alike), so it mutates byUri directly via applyRegister/
+// applyUnregister rather than calling back into Register/Unregister: those
+// already ran (and could have rejected) the registration's hooks exactly
+// once, on whichever node originated the command, before it was ever
+// submitted to the Raft log. Running hooks again here would fire them once
+// per cluster node instead of once per event, and - since a register hook
+// can reject - could let replicas that apply the same log entry disagree.
+type fsm struct {
+	registry *RouteRegistry
+}
+
+func newFSM(registry *RouteRegistry) *fsm {
+	return &fsm{registry: registry}
+}
+
+func (f *fsm) Apply(log *raft.Log) interface{} {
+	var cmd command
+	if err := json.Unmarshal(log.Data, &cmd); err != nil {
+		return err
+	}
+
+	switch cmd.Op {
+	case "register":
+		f.registry.applyRegister(cmd.Uri, cmd.Endpoint)
+	case "unregister":
+		f.registry.applyUnregister(cmd.Uri, cmd.Endpoint)
+	}
+
+	return nil
+}
+
+func (f *fsm) Snapshot() (raft.FSMSnapshot, error) {
+	data, err := f.registry.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	return &fsmSnapshot{data: data}, nil
+}
+
+// Restore replaces the local registry's state with a snapshot taken
+// earlier (by this node or another). It goes through applyRegister, not
+// Register, so restoring a snapshot can never be rejected by a register
+// hook - the endpoints it contains were already admitted once, when they
+// were first registered.
+func (f *fsm) Restore(rc io.ReadCloser) error {
+	defer rc.Close()
+
+	var byUri map[route.Uri][]*route.Endpoint
+	if err := json.NewDecoder(rc).Decode(&byUri); err != nil {
+		return err
+	}
+
+	f.registry.Lock()
+	f.registry.byUri = make(map[route.Uri]*route.Pool, len(byUri))
+	f.registry.Unlock()
+
+	for uri, endpoints := range byUri {
+		for _, endpoint := range endpoints {
+			f.registry.applyRegister(uri, endpoint)
+		}
+	}
+
+	return nil
+}
+
+type fsmSnapshot struct {
+	data []byte
+}
+
+func (s *fsmSnapshot) Persist(sink raft.SnapshotSink) error {
+	if _, err := sink.Write(s.data); err != nil {
+		sink.Cancel()
+		return err
+	}
+
+	return sink.Close()
+}
+
+func (s *fsmSnapshot) Release() {}
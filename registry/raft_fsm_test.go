@@ -0,0 +1,140 @@
+package registry
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/raft"
+
+	"github.com/hjinkim-cf1/gorouter/config"
+	"github.com/hjinkim-cf1/gorouter/route"
+)
+
+func newTestRegistry() *RouteRegistry {
+	c := config.DefaultConfig()
+	c.DropletStaleThreshold = time.Minute
+	return NewRouteRegistry(c, nil)
+}
+
+func applyCommand(t *testing.T, f *fsm, cmd command) {
+	t.Helper()
+
+	data, err := json.Marshal(cmd)
+	if err != nil {
+		t.Fatalf("marshal command: %s", err)
+	}
+
+	if result := f.Apply(&raft.Log{Data: data}); result != nil {
+		t.Fatalf("Apply returned unexpected error: %v", result)
+	}
+}
+
+func TestFSMApplyRegisterAndUnregister(t *testing.T) {
+	registry := newTestRegistry()
+	f := newFSM(registry)
+
+	endpoint := route.NewEndpoint("app", "10.0.0.1", 1234, "a", "", nil)
+
+	applyCommand(t, f, command{Op: "register", Uri: "foo.com", Endpoint: endpoint})
+
+	pool := registry.Lookup("foo.com")
+	if pool == nil || pool.IsEmpty() {
+		t.Fatal("expected foo.com to have an endpoint after a register command")
+	}
+
+	applyCommand(t, f, command{Op: "unregister", Uri: "foo.com", Endpoint: endpoint})
+
+	if pool := registry.Lookup("foo.com"); pool != nil {
+		t.Fatal("expected foo.com's pool to be gone after its only endpoint was unregistered")
+	}
+}
+
+func TestFSMApplyRegisterBypassesHooks(t *testing.T) {
+	registry := newTestRegistry()
+	registry.RegisterHook(HookRegister, HookFunc(func(uri route.Uri, endpoint *route.Endpoint) error {
+		return errors.New("rejected")
+	}))
+
+	f := newFSM(registry)
+	endpoint := route.NewEndpoint("app", "10.0.0.1", 1234, "a", "", nil)
+
+	applyCommand(t, f, command{Op: "register", Uri: "foo.com", Endpoint: endpoint})
+
+	if pool := registry.Lookup("foo.com"); pool == nil || pool.IsEmpty() {
+		t.Fatal("expected Apply to register via applyRegister, bypassing the register hook")
+	}
+}
+
+func TestFSMApplyUnknownOpIsANoop(t *testing.T) {
+	registry := newTestRegistry()
+	f := newFSM(registry)
+
+	applyCommand(t, f, command{Op: "bogus", Uri: "foo.com", Endpoint: route.NewEndpoint("app", "10.0.0.1", 1234, "a", "", nil)})
+
+	if pool := registry.Lookup("foo.com"); pool != nil {
+		t.Fatal("expected an unrecognized op to leave the registry untouched")
+	}
+}
+
+func TestFSMApplyMalformedLogReturnsError(t *testing.T) {
+	registry := newTestRegistry()
+	f := newFSM(registry)
+
+	if result := f.Apply(&raft.Log{Data: []byte("not json")}); result == nil {
+		t.Fatal("expected Apply to return an error for malformed log data")
+	}
+}
+
+func TestFSMSnapshotAndRestore(t *testing.T) {
+	source := newTestRegistry()
+	source.applyRegister("foo.com", route.NewEndpoint("app", "10.0.0.1", 1234, "a", "", nil))
+	source.applyRegister("bar.com", route.NewEndpoint("app", "10.0.0.2", 5678, "b", "", nil))
+
+	snapshot, err := newFSM(source).Snapshot()
+	if err != nil {
+		t.Fatalf("Snapshot: %s", err)
+	}
+
+	var buf bytes.Buffer
+	if err := snapshot.Persist(&persistSink{Buffer: &buf}); err != nil {
+		t.Fatalf("Persist: %s", err)
+	}
+
+	target := newTestRegistry()
+	target.applyRegister("foo.com", route.NewEndpoint("app", "10.0.0.9", 9999, "stale", "", nil))
+
+	if err := newFSM(target).Restore(io.NopCloser(&buf)); err != nil {
+		t.Fatalf("Restore: %s", err)
+	}
+
+	fooPool := target.Lookup("foo.com")
+	if fooPool == nil {
+		t.Fatal("expected foo.com to survive Restore")
+	}
+	seen := map[string]bool{}
+	fooPool.Each(func(e *route.Endpoint) { seen[e.PrivateInstanceId] = true })
+	if seen["stale"] {
+		t.Fatal("expected Restore to replace target's prior state, not merge with it")
+	}
+	if !seen["a"] {
+		t.Fatal("expected foo.com's snapshot endpoint to be present after Restore")
+	}
+
+	if barPool := target.Lookup("bar.com"); barPool == nil || barPool.IsEmpty() {
+		t.Fatal("expected bar.com to be restored from the snapshot")
+	}
+}
+
+// persistSink is a minimal raft.SnapshotSink that writes to an in-memory
+// buffer, enough for fsmSnapshot.Persist to exercise against.
+type persistSink struct {
+	*bytes.Buffer
+}
+
+func (s *persistSink) ID() string    { return "test" }
+func (s *persistSink) Cancel() error { return nil }
+func (s *persistSink) Close() error  { return nil }
@@ -0,0 +1,284 @@
+package registry
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/hashicorp/raft"
+	raftboltdb "github.com/hashicorp/raft-boltdb"
+
+	steno "github.com/cloudfoundry/gosteno"
+	"github.com/cloudfoundry/yagnats"
+
+	"github.com/hjinkim-cf1/gorouter/config"
+	"github.com/hjinkim-cf1/gorouter/route"
+)
+
+var _ RegistryInterface = (*ReplicatedRouteRegistry)(nil)
+
+// ReplicatedRouteRegistry is a RegistryInterface backed by a Raft log, so
+// route state stays consistent across a cluster of gorouters even through
+// a NATS partition. Register/Unregister submit Raft log entries; Lookup
+// and the Num* accessors are local reads against whatever the log has
+// applied so far.
+type ReplicatedRouteRegistry struct {
+	local *RouteRegistry
+	fsm   *fsm
+	raft  *raft.Raft
+
+	logger *steno.Logger
+}
+
+// NewReplicatedRouteRegistry brings up the Raft subsystem described by
+// c.Registry and wraps a fresh RouteRegistry as its FSM. c.Registry.Mode
+// must be "raft"; callers should otherwise use NewRouteRegistry directly.
+func NewReplicatedRouteRegistry(c *config.Config, mbus yagnats.NATSConn) (*ReplicatedRouteRegistry, error) {
+	local := NewRouteRegistry(c, mbus)
+
+	r := &ReplicatedRouteRegistry{
+		local:  local,
+		fsm:    newFSM(local),
+		logger: steno.NewLogger("router.registry.raft"),
+	}
+
+	if err := os.MkdirAll(c.Registry.DataDir, 0755); err != nil {
+		return nil, fmt.Errorf("raft data dir: %s", err)
+	}
+
+	raftConfig := raft.DefaultConfig()
+	raftConfig.LocalID = raft.ServerID(fmt.Sprintf("%s:%d", c.Ip, c.Port))
+	if c.Registry.HeartbeatTimeout > 0 {
+		raftConfig.HeartbeatTimeout = c.Registry.HeartbeatTimeout
+	}
+
+	addr := fmt.Sprintf("%s:%d", c.Ip, c.Port)
+	transport, err := raft.NewTCPTransport(addr, nil, 3, 10*time.Second, os.Stderr)
+	if err != nil {
+		return nil, fmt.Errorf("raft transport: %s", err)
+	}
+
+	snapshots, err := raft.NewFileSnapshotStore(c.Registry.DataDir, 2, os.Stderr)
+	if err != nil {
+		return nil, fmt.Errorf("raft snapshot store: %s", err)
+	}
+
+	store, err := raftboltdb.NewBoltStore(filepath.Join(c.Registry.DataDir, "raft.db"))
+	if err != nil {
+		return nil, fmt.Errorf("raft log/stable store: %s", err)
+	}
+
+	ra, err := raft.NewRaft(raftConfig, r.fsm, store, store, snapshots, transport)
+	if err != nil {
+		return nil, fmt.Errorf("raft node: %s", err)
+	}
+	r.raft = ra
+
+	if len(c.Registry.RaftPeers) == 0 {
+		bootstrap := raft.Configuration{
+			Servers: []raft.Server{{ID: raftConfig.LocalID, Address: transport.LocalAddr()}},
+		}
+		ra.BootstrapCluster(bootstrap)
+	}
+
+	return r, nil
+}
+
+func (r *ReplicatedRouteRegistry) isLeader() bool {
+	return r.raft.State() == raft.Leader
+}
+
+func (r *ReplicatedRouteRegistry) apply(cmd command) error {
+	if !r.isLeader() {
+		return r.forwardToLeader(cmd)
+	}
+
+	data, err := json.Marshal(cmd)
+	if err != nil {
+		return err
+	}
+
+	return r.raft.Apply(data, 5*time.Second).Error()
+}
+
+// forwardToLeader proxies a command a follower received via NATS on to
+// whichever node the Raft cluster currently considers leader, via the
+// admin HTTP surface's /raft/forward endpoint.
+func (r *ReplicatedRouteRegistry) forwardToLeader(cmd command) error {
+	leader := r.raft.Leader()
+	if leader == "" {
+		return fmt.Errorf("no raft leader available")
+	}
+
+	data, err := json.Marshal(cmd)
+	if err != nil {
+		return err
+	}
+
+	resp, err := http.Post(fmt.Sprintf("http://%s/raft/forward", leader), "application/json", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("leader rejected forwarded command: %s", resp.Status)
+	}
+
+	return nil
+}
+
+// Register runs the local registry's register hooks exactly once - so a
+// rejection (e.g. a quota hook) happens before anything is submitted to the
+// Raft log - then replicates the accepted registration across the cluster.
+// fsm.Apply applies the resulting log entry on every node via
+// applyRegister, without running hooks again.
+func (r *ReplicatedRouteRegistry) Register(uri route.Uri, endpoint *route.Endpoint) error {
+	uri = uri.ToLower()
+
+	if err := r.local.hooks.run(HookRegister, uri, endpoint.Snapshot()); err != nil {
+		r.logger.Warnf("rejecting registration of %s for %s: %s", endpoint.CanonicalAddr(), uri, err)
+		return err
+	}
+
+	if err := r.apply(command{Op: "register", Uri: uri, Endpoint: endpoint}); err != nil {
+		r.logger.Errorf("failed to replicate registration for %s: %s", uri, err)
+		return err
+	}
+
+	return nil
+}
+
+// Unregister replicates the removal across the cluster, then runs the local
+// registry's unregister hooks once if replication succeeded; see Register.
+func (r *ReplicatedRouteRegistry) Unregister(uri route.Uri, endpoint *route.Endpoint) {
+	uri = uri.ToLower()
+
+	if err := r.apply(command{Op: "unregister", Uri: uri, Endpoint: endpoint}); err != nil {
+		r.logger.Errorf("failed to replicate unregistration for %s: %s", uri, err)
+		return
+	}
+
+	if err := r.local.hooks.run(HookUnregister, uri, endpoint.Snapshot()); err != nil {
+		r.logger.Warnf("unregister hook errored for %s: %s", uri, err)
+	}
+}
+
+func (r *ReplicatedRouteRegistry) Lookup(uri route.Uri) *route.Pool {
+	return r.local.Lookup(uri)
+}
+
+// StartPruningCycle only actually prunes on the leader; followers still run
+// the ticker so they pick up leadership without a restart, but skip the
+// work to avoid diverging from the log.
+func (r *ReplicatedRouteRegistry) StartPruningCycle() {
+	if r.local.pruneStaleDropletsInterval <= 0 {
+		return
+	}
+
+	r.local.Lock()
+	r.local.ticker = time.NewTicker(r.local.pruneStaleDropletsInterval)
+	r.local.Unlock()
+
+	go func() {
+		for range r.local.ticker.C {
+			if r.isLeader() {
+				r.local.pruneStaleDroplets()
+			}
+		}
+	}()
+}
+
+func (r *ReplicatedRouteRegistry) StopPruningCycle() {
+	r.local.StopPruningCycle()
+}
+
+func (r *ReplicatedRouteRegistry) NumUris() int {
+	return r.local.NumUris()
+}
+
+func (r *ReplicatedRouteRegistry) NumEndpoints() int {
+	return r.local.NumEndpoints()
+}
+
+func (r *ReplicatedRouteRegistry) MarshalJSON() ([]byte, error) {
+	return r.local.MarshalJSON()
+}
+
+// AdminHandler serves the join/leave/stats/forward surface described
+// alongside common.Healthz, for cluster membership changes and operator
+// visibility into Raft state.
+func (r *ReplicatedRouteRegistry) AdminHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/raft/join", r.handleJoin)
+	mux.HandleFunc("/raft/leave", r.handleLeave)
+	mux.HandleFunc("/raft/stats", r.handleStats)
+	mux.HandleFunc("/raft/forward", r.handleForward)
+	return mux
+}
+
+func (r *ReplicatedRouteRegistry) handleJoin(w http.ResponseWriter, req *http.Request) {
+	var body struct {
+		ID      string `json:"id"`
+		Address string `json:"address"`
+	}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	future := r.raft.AddVoter(raft.ServerID(body.ID), raft.ServerAddress(body.Address), 0, 0)
+	if err := future.Error(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (r *ReplicatedRouteRegistry) handleLeave(w http.ResponseWriter, req *http.Request) {
+	var body struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	future := r.raft.RemoveServer(raft.ServerID(body.ID), 0, 0)
+	if err := future.Error(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (r *ReplicatedRouteRegistry) handleStats(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(r.raft.Stats())
+}
+
+func (r *ReplicatedRouteRegistry) handleForward(w http.ResponseWriter, req *http.Request) {
+	if !r.isLeader() {
+		http.Error(w, "not leader", http.StatusServiceUnavailable)
+		return
+	}
+
+	var cmd command
+	if err := json.NewDecoder(req.Body).Decode(&cmd); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := r.apply(cmd); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
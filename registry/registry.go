@@ -13,7 +13,7 @@ import (
 )
 
 type RegistryInterface interface {
-	Register(uri route.Uri, endpoint *route.Endpoint)
+	Register(uri route.Uri, endpoint *route.Endpoint) error
 	Unregister(uri route.Uri, endpoint *route.Endpoint)
 	Lookup(uri route.Uri) *route.Pool
 	StartPruningCycle()
@@ -33,6 +33,16 @@ type RouteRegistry struct {
 	pruneStaleDropletsInterval time.Duration
 	dropletStaleThreshold      time.Duration
 
+	zone             string
+	zoneAffinityMode string
+	zoneSpreadTarget map[string]int
+
+	loadBalancers map[route.Uri]LoadBalancer
+
+	tagRouter *TagRouter
+
+	hooks *hookRegistry
+
 	messageBus yagnats.NATSConn
 
 	ticker           *time.Ticker
@@ -45,53 +55,176 @@ func NewRouteRegistry(c *config.Config, mbus yagnats.NATSConn) *RouteRegistry {
 	r.logger = steno.NewLogger("router.registry")
 
 	r.byUri = make(map[route.Uri]*route.Pool)
+	r.loadBalancers = make(map[route.Uri]LoadBalancer)
+	r.tagRouter = NewTagRouter()
+	r.hooks = newHookRegistry()
 
 	r.pruneStaleDropletsInterval = c.PruneStaleDropletsInterval
 	r.dropletStaleThreshold = c.DropletStaleThreshold
 
+	r.zone = c.Zone
+	r.zoneAffinityMode = c.ZoneAffinity.Mode
+	r.zoneSpreadTarget = c.ZoneAffinity.Spread
+
 	r.messageBus = mbus
 
+	if mbus != nil {
+		if err := r.tagRouter.SubscribeToUpdates(mbus); err != nil {
+			r.logger.Errorf("failed to subscribe to %s: %s", tagRulesSubject, err)
+		}
+
+		if c.PublishRegistryEvents {
+			for _, stage := range []string{HookRegister, HookUnregister, HookPrune} {
+				r.RegisterHook(stage, NewNATSEventHook(mbus, stage))
+			}
+		}
+	}
+
 	return r
 }
 
-func (r *RouteRegistry) Register(uri route.Uri, endpoint *route.Endpoint) {
-	t := time.Now()
+// Register adds endpoint to uri's pool, unless a "register" hook rejects
+// it - e.g. to block routes to unapproved hosts or enforce a max
+// endpoints-per-URI quota.
+func (r *RouteRegistry) Register(uri route.Uri, endpoint *route.Endpoint) error {
+	uri = uri.ToLower()
+
 	r.Lock()
+	defer r.Unlock()
+
+	if err := r.hooks.run(HookRegister, uri, endpoint.Snapshot()); err != nil {
+		r.logger.Warnf("rejecting registration of %s for %s: %s", endpoint.CanonicalAddr(), uri, err)
+		return err
+	}
+
+	r.putLocked(uri, endpoint)
 
+	return nil
+}
+
+// applyRegister adds endpoint to uri's pool without running register hooks.
+// It's used to apply an already-admitted registration - one whose hooks ran
+// once, before the change was replicated - without re-running (and
+// potentially re-rejecting) them: see ReplicatedRouteRegistry and fsm.Apply.
+func (r *RouteRegistry) applyRegister(uri route.Uri, endpoint *route.Endpoint) {
 	uri = uri.ToLower()
 
+	r.Lock()
+	defer r.Unlock()
+
+	r.putLocked(uri, endpoint)
+}
+
+func (r *RouteRegistry) putLocked(uri route.Uri, endpoint *route.Endpoint) {
 	pool, found := r.byUri[uri]
 	if !found {
-		pool = route.NewPool(r.dropletStaleThreshold / 4)
+		pool = route.NewPool(r.dropletStaleThreshold/4, r.zone, r.zoneAffinityMode, r.zoneSpreadTarget)
 		r.byUri[uri] = pool
 	}
 
 	pool.Put(endpoint)
 
-	r.timeOfLastUpdate = t
-	r.Unlock()
+	if _, found := r.loadBalancers[uri]; !found && endpoint.LBAlgorithm != "" {
+		r.loadBalancers[uri] = NewLoadBalancer(endpoint.LBAlgorithm)
+	}
+
+	r.timeOfLastUpdate = time.Now()
+}
+
+// LoadBalancer returns the load-balancing algorithm registered for uri,
+// defaulting to round-robin if none was specified at registration time. It
+// resolves wildcards the same way Lookup does, so a uri that only matches
+// via e.g. *.bar.com gets that registration's algorithm, not the default.
+func (r *RouteRegistry) LoadBalancer(uri route.Uri) LoadBalancer {
+	r.RLock()
+	defer r.RUnlock()
+
+	matched, _ := r.resolve(uri)
+	if lb, found := r.loadBalancers[matched]; found {
+		return lb
+	}
+
+	return NewLoadBalancer("")
+}
+
+// TagRouter returns the registry's tag router, which the proxy consults
+// after Lookup to narrow a route's pool down by request tags.
+func (r *RouteRegistry) TagRouter() *TagRouter {
+	return r.tagRouter
+}
+
+// VarzLoadBalancers returns the per-URI load-balancing algorithm and
+// request counters, for inclusion in the router's /varz output.
+func (r *RouteRegistry) VarzLoadBalancers() map[string]LoadBalancerVarz {
+	r.RLock()
+	defer r.RUnlock()
+
+	out := make(map[string]LoadBalancerVarz, len(r.loadBalancers))
+	for uri, lb := range r.loadBalancers {
+		out[uri.String()] = varzFor(lb)
+	}
+
+	return out
+}
+
+// NextEndpoint resolves the pool for uri and returns the next endpoint to
+// try per that route's load-balancing algorithm. remoteAddr is only
+// consulted by algorithms (ip_hash) that need client affinity.
+func (r *RouteRegistry) NextEndpoint(uri route.Uri, remoteAddr string) *route.Endpoint {
+	pool := r.Lookup(uri)
+	if pool == nil {
+		return nil
+	}
+
+	return r.LoadBalancer(uri).Next(pool, remoteAddr)
 }
 
 func (r *RouteRegistry) Unregister(uri route.Uri, endpoint *route.Endpoint) {
-	r.Lock()
+	uri = uri.ToLower()
+	removed := r.applyUnregister(uri, endpoint)
+
+	if removed {
+		if err := r.hooks.run(HookUnregister, uri, endpoint.Snapshot()); err != nil {
+			r.logger.Warnf("unregister hook errored for %s: %s", uri, err)
+		}
+	}
+}
 
+// applyUnregister removes endpoint from uri's pool without running
+// unregister hooks; see applyRegister.
+func (r *RouteRegistry) applyUnregister(uri route.Uri, endpoint *route.Endpoint) bool {
 	uri = uri.ToLower()
 
+	r.Lock()
+	defer r.Unlock()
+
 	pool, found := r.byUri[uri]
+	removed := false
 	if found {
-		pool.Remove(endpoint)
+		removed = pool.Remove(endpoint)
 
 		if pool.IsEmpty() {
 			delete(r.byUri, uri)
+			delete(r.loadBalancers, uri)
 		}
 	}
 
-	r.Unlock()
+	return removed
 }
 
 func (r *RouteRegistry) Lookup(uri route.Uri) *route.Pool {
 	r.RLock()
+	defer r.RUnlock()
+
+	_, pool := r.resolve(uri)
+
+	return pool
+}
 
+// resolve walks uri's wildcard ancestors (e.g. myapp.bar.com, then
+// *.bar.com) until it finds a registered pool, returning the uri that
+// actually matched alongside it. Callers must hold at least r.RLock().
+func (r *RouteRegistry) resolve(uri route.Uri) (route.Uri, *route.Pool) {
 	uri = uri.ToLower()
 	var err error
 	pool, found := r.byUri[uri]
@@ -100,9 +233,7 @@ func (r *RouteRegistry) Lookup(uri route.Uri) *route.Pool {
 		pool, found = r.byUri[uri]
 	}
 
-	r.RUnlock()
-
-	return pool
+	return uri, pool
 }
 
 func (r *RouteRegistry) StartPruningCycle() {
@@ -170,13 +301,25 @@ func (r *RouteRegistry) MarshalJSON() ([]byte, error) {
 
 func (r *RouteRegistry) pruneStaleDroplets() {
 	r.Lock()
+	pruned := make(map[route.Uri][]*route.Endpoint)
 	for k, pool := range r.byUri {
-		pool.PruneEndpoints(r.dropletStaleThreshold)
+		if removed := pool.PruneEndpoints(r.dropletStaleThreshold); len(removed) > 0 {
+			pruned[k] = removed
+		}
 		if pool.IsEmpty() {
 			delete(r.byUri, k)
+			delete(r.loadBalancers, k)
 		}
 	}
 	r.Unlock()
+
+	for uri, endpoints := range pruned {
+		for _, endpoint := range endpoints {
+			if err := r.hooks.run(HookPrune, uri, endpoint.Snapshot()); err != nil {
+				r.logger.Warnf("prune hook errored for %s: %s", uri, err)
+			}
+		}
+	}
 }
 
 func (r *RouteRegistry) pauseStaleTracker() {
@@ -0,0 +1,178 @@
+package registry
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	steno "github.com/cloudfoundry/gosteno"
+	"github.com/cloudfoundry/yagnats"
+	nats "github.com/nats-io/nats.go"
+
+	"github.com/hjinkim-cf1/gorouter/route"
+)
+
+// tagRulesSubject is the NATS subject tag rule updates are published on so
+// every router in the cluster picks them up without a restart.
+const tagRulesSubject = "router.tag_rules"
+
+// TagMatch selects which requests a TagRule applies to, by header value.
+type TagMatch struct {
+	Header string `json:"header"`
+	Value  string `json:"value"`
+}
+
+// TagRule steers a request to the subset of a route's endpoints whose
+// registered Tags satisfy Require, once Match picks out the request. It
+// enables canary, blue/green, and A/B rollouts without changing the URI
+// space: e.g. {Match: {Header: "X-Canary", Value: "true"}, Require:
+// {"version": "v2"}, Fallback: "none"}.
+type TagRule struct {
+	Match    TagMatch          `json:"match"`
+	Require  map[string]string `json:"require"`
+	Fallback string            `json:"fallback"` // "any" or "none"
+}
+
+// tagFilterKey identifies one rule's narrowed view of one route's pool, so
+// TagRouter can cache and keep rotating that narrowed pool across requests
+// instead of rebuilding (and re-zeroing its rotation cursor) every time.
+type tagFilterKey struct {
+	pool *route.Pool
+	rule int
+}
+
+// TagRouter holds an ordered list of TagRules and narrows a route.Pool down
+// to the endpoints a matching rule requires.
+type TagRouter struct {
+	sync.RWMutex
+
+	rules    []TagRule
+	filtered map[tagFilterKey]*route.Pool
+
+	logger *steno.Logger
+}
+
+func NewTagRouter() *TagRouter {
+	return &TagRouter{
+		filtered: make(map[tagFilterKey]*route.Pool),
+		logger:   steno.NewLogger("router.registry.tag_router"),
+	}
+}
+
+// SetRules replaces the rule set and drops any cached narrowed pools, since
+// a rule's position (and so its tagFilterKey) may now refer to a different
+// rule entirely.
+func (t *TagRouter) SetRules(rules []TagRule) {
+	t.Lock()
+	t.rules = rules
+	t.filtered = make(map[tagFilterKey]*route.Pool)
+	t.Unlock()
+}
+
+func (t *TagRouter) Rules() []TagRule {
+	t.RLock()
+	defer t.RUnlock()
+
+	rules := make([]TagRule, len(t.rules))
+	copy(rules, t.rules)
+
+	return rules
+}
+
+// Resolve narrows pool down to the endpoints required by the first rule
+// whose Match matches req. ok is false only when a matching rule's
+// Fallback is "none" and no endpoint satisfies Require - callers should
+// return 503 in that case. With no matching rule, pool is returned as-is.
+func (t *TagRouter) Resolve(req *http.Request, pool *route.Pool) (resolved *route.Pool, ok bool) {
+	if pool == nil {
+		return nil, true
+	}
+
+	t.Lock()
+	defer t.Unlock()
+
+	for i, rule := range t.rules {
+		if req.Header.Get(rule.Match.Header) != rule.Match.Value {
+			continue
+		}
+
+		tagged := t.filteredPool(pool, i, rule)
+
+		if !tagged.IsEmpty() {
+			return tagged, true
+		}
+
+		if rule.Fallback == "none" {
+			return nil, false
+		}
+
+		return pool, true
+	}
+
+	return pool, true
+}
+
+// filteredPool returns rule's narrowed view of pool, keyed by pool identity
+// and rule position. The first resolution for a key creates the narrowed
+// Pool via Filter (so it inherits pool's zone-affinity settings); every
+// later resolution reuses that same Pool and just resyncs its membership,
+// so its round-robin/spread cursor keeps advancing across requests instead
+// of restarting at zero - which would otherwise always hand back the first
+// matching endpoint. Callers must hold t's lock.
+func (t *TagRouter) filteredPool(pool *route.Pool, ruleIdx int, rule TagRule) *route.Pool {
+	match := func(endpoint *route.Endpoint) bool {
+		return matchesTags(endpoint.Tags, rule.Require)
+	}
+
+	key := tagFilterKey{pool: pool, rule: ruleIdx}
+
+	cached, ok := t.filtered[key]
+	if !ok {
+		cached = pool.Filter(match)
+		t.filtered[key] = cached
+		return cached
+	}
+
+	var matched []*route.Endpoint
+	pool.Each(func(endpoint *route.Endpoint) {
+		if match(endpoint) {
+			matched = append(matched, endpoint)
+		}
+	})
+	cached.SyncEntries(matched)
+
+	return cached
+}
+
+func matchesTags(endpointTags, require map[string]string) bool {
+	for k, v := range require {
+		if endpointTags[k] != v {
+			return false
+		}
+	}
+
+	return true
+}
+
+// SubscribeToUpdates hot-reloads rules published on tagRulesSubject, so
+// `/routes/tags` and route resolution pick up operator changes without a
+// restart.
+func (t *TagRouter) SubscribeToUpdates(mbus yagnats.NATSConn) error {
+	_, err := mbus.Subscribe(tagRulesSubject, func(msg *nats.Msg) {
+		var rules []TagRule
+		if err := json.Unmarshal(msg.Data, &rules); err != nil {
+			t.logger.Errorf("failed to parse %s payload: %s", tagRulesSubject, err)
+			return
+		}
+
+		t.SetRules(rules)
+	})
+
+	return err
+}
+
+// ServeHTTP exposes the current rule set at /routes/tags for inspection.
+func (t *TagRouter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(t.Rules())
+}
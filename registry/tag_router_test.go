@@ -0,0 +1,129 @@
+package registry_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/hjinkim-cf1/gorouter/registry"
+	"github.com/hjinkim-cf1/gorouter/route"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("TagRouter", func() {
+	var (
+		pool *route.Pool
+		a, b *route.Endpoint
+	)
+
+	BeforeEach(func() {
+		pool = route.NewPool(time.Minute, "", "", nil)
+
+		a = route.NewEndpoint("app", "10.0.0.1", 1, "a", "", map[string]string{"version": "v2"})
+		b = route.NewEndpoint("app", "10.0.0.2", 2, "b", "", map[string]string{"version": "v2"})
+		pool.Put(a)
+		pool.Put(b)
+		pool.Put(route.NewEndpoint("app", "10.0.0.3", 3, "stable", "", map[string]string{"version": "v1"}))
+	})
+
+	canaryRequest := func() *http.Request {
+		req := httptest.NewRequest("GET", "/", nil)
+		req.Header.Set("X-Canary", "true")
+		return req
+	}
+
+	It("returns the pool unmodified when no rule matches", func() {
+		tr := NewTagRouter()
+		tr.SetRules([]TagRule{{
+			Match:   TagMatch{Header: "X-Canary", Value: "true"},
+			Require: map[string]string{"version": "v2"},
+		}})
+
+		resolved, ok := tr.Resolve(httptest.NewRequest("GET", "/", nil), pool)
+		Ω(ok).Should(BeTrue())
+		Ω(resolved).Should(Equal(pool))
+	})
+
+	It("narrows the pool to endpoints satisfying the matching rule's Require", func() {
+		tr := NewTagRouter()
+		tr.SetRules([]TagRule{{
+			Match:   TagMatch{Header: "X-Canary", Value: "true"},
+			Require: map[string]string{"version": "v2"},
+		}})
+
+		resolved, ok := tr.Resolve(canaryRequest(), pool)
+		Ω(ok).Should(BeTrue())
+
+		seen := map[string]bool{}
+		resolved.Each(func(e *route.Endpoint) { seen[e.PrivateInstanceId] = true })
+		Ω(seen).Should(Equal(map[string]bool{"a": true, "b": true}))
+	})
+
+	It("returns not-ok when Require matches nothing and Fallback is none", func() {
+		tr := NewTagRouter()
+		tr.SetRules([]TagRule{{
+			Match:    TagMatch{Header: "X-Canary", Value: "true"},
+			Require:  map[string]string{"version": "v3"},
+			Fallback: "none",
+		}})
+
+		resolved, ok := tr.Resolve(canaryRequest(), pool)
+		Ω(ok).Should(BeFalse())
+		Ω(resolved).Should(BeNil())
+	})
+
+	It("falls back to the full pool when Require matches nothing and Fallback is any", func() {
+		tr := NewTagRouter()
+		tr.SetRules([]TagRule{{
+			Match:    TagMatch{Header: "X-Canary", Value: "true"},
+			Require:  map[string]string{"version": "v3"},
+			Fallback: "any",
+		}})
+
+		resolved, ok := tr.Resolve(canaryRequest(), pool)
+		Ω(ok).Should(BeTrue())
+		Ω(resolved).Should(Equal(pool))
+	})
+
+	It("keeps rotating the narrowed pool across repeated Resolve calls instead of resetting it", func() {
+		tr := NewTagRouter()
+		tr.SetRules([]TagRule{{
+			Match:   TagMatch{Header: "X-Canary", Value: "true"},
+			Require: map[string]string{"version": "v2"},
+		}})
+
+		counts := map[string]int{}
+		for i := 0; i < 10; i++ {
+			resolved, ok := tr.Resolve(canaryRequest(), pool)
+			Ω(ok).Should(BeTrue())
+			counts[resolved.Next().PrivateInstanceId]++
+		}
+
+		Ω(counts["a"]).Should(Equal(5))
+		Ω(counts["b"]).Should(Equal(5))
+	})
+
+	It("drops cached narrowed pools when rules are replaced", func() {
+		tr := NewTagRouter()
+		tr.SetRules([]TagRule{{
+			Match:   TagMatch{Header: "X-Canary", Value: "true"},
+			Require: map[string]string{"version": "v2"},
+		}})
+
+		tr.Resolve(canaryRequest(), pool)
+
+		tr.SetRules([]TagRule{{
+			Match:   TagMatch{Header: "X-Canary", Value: "true"},
+			Require: map[string]string{"version": "v1"},
+		}})
+
+		resolved, ok := tr.Resolve(canaryRequest(), pool)
+		Ω(ok).Should(BeTrue())
+
+		seen := map[string]bool{}
+		resolved.Each(func(e *route.Endpoint) { seen[e.PrivateInstanceId] = true })
+		Ω(seen).Should(Equal(map[string]bool{"stable": true}))
+	})
+})
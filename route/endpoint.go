@@ -0,0 +1,92 @@
+package route
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// Endpoint is a single backend instance registered for one or more URIs.
+type Endpoint struct {
+	ApplicationId string
+	Host          string
+	Port          uint16
+
+	PrivateInstanceId string
+
+	// Zone is the availability zone the endpoint was registered from, as
+	// advertised by the gorouter instance that owns it. Used by the
+	// registry to implement zone-affine lookups.
+	Zone string
+
+	// LBAlgorithm is the load-balancing algorithm requested at
+	// registration time for the URI this endpoint backs (e.g.
+	// "round_robin", "least_connections", "ip_hash",
+	// "weighted_round_robin"). Endpoints for the same URI are expected to
+	// agree; the registry honors whichever was seen first.
+	LBAlgorithm string
+
+	// Weight is used by the weighted_round_robin algorithm. Endpoints
+	// that don't set it are treated as weight 1.
+	Weight int
+
+	// UseProxyProtocol is "v1", "v2", or "" (the default, plain TCP/TLS).
+	// When set, the proxy writes the corresponding PROXY protocol header
+	// immediately after dialing this endpoint, before any TLS or HTTP
+	// bytes, so the backend sees the original client address.
+	UseProxyProtocol string
+
+	Tags map[string]string
+
+	connections int64
+}
+
+func NewEndpoint(applicationId, host string, port uint16, privateInstanceId, zone string, tags map[string]string) *Endpoint {
+	return &Endpoint{
+		ApplicationId:     applicationId,
+		Host:              host,
+		Port:              port,
+		PrivateInstanceId: privateInstanceId,
+		Zone:              zone,
+		Tags:              tags,
+	}
+}
+
+func (e *Endpoint) CanonicalAddr() string {
+	return fmt.Sprintf("%s:%d", e.Host, e.Port)
+}
+
+func (e *Endpoint) Equal(other *Endpoint) bool {
+	return e.CanonicalAddr() == other.CanonicalAddr()
+}
+
+// IncrementConnections records the start of a request to this endpoint. The
+// proxy calls it right before dialing and DecrementConnections once the
+// response has been returned, so ConnectionCount reflects in-flight
+// requests for the least_connections load-balancing algorithm.
+func (e *Endpoint) IncrementConnections() int64 {
+	return atomic.AddInt64(&e.connections, 1)
+}
+
+func (e *Endpoint) DecrementConnections() int64 {
+	return atomic.AddInt64(&e.connections, -1)
+}
+
+func (e *Endpoint) ConnectionCount() int64 {
+	return atomic.LoadInt64(&e.connections)
+}
+
+// Snapshot returns a copy of e safe to hand to code outside the registry
+// (e.g. a lifecycle hook) without it being able to mutate live state.
+func (e *Endpoint) Snapshot() *Endpoint {
+	snapshot := *e
+	snapshot.connections = e.ConnectionCount()
+
+	if e.Tags != nil {
+		snapshot.Tags = make(map[string]string, len(e.Tags))
+		for k, v := range e.Tags {
+			snapshot.Tags[k] = v
+		}
+	}
+
+	return &snapshot
+}
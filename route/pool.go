@@ -0,0 +1,300 @@
+package route
+
+import (
+	"encoding/json"
+	"math"
+	"sync"
+	"time"
+)
+
+type poolEntry struct {
+	endpoint  *Endpoint
+	updatedAt time.Time
+}
+
+// Pool holds the set of endpoints registered for a single route (URI) and
+// hands them out to the proxy one at a time via Next, rotating through the
+// set in round-robin order.
+//
+// When localZone is non-empty, Next is zone-affine: the affinityMode
+// ("prefer", "strict", "spread") decides how endpoints outside localZone
+// are weighed against endpoints inside it.
+type Pool struct {
+	sync.Mutex
+
+	retryAfterFailure time.Duration
+
+	localZone    string
+	affinityMode string
+	spreadTarget map[string]int
+	spreadCount  map[string]int
+
+	entries  []*poolEntry
+	zoneNext map[string]int
+}
+
+func NewPool(retryAfterFailure time.Duration, localZone, affinityMode string, spreadTarget map[string]int) *Pool {
+	if affinityMode == "" {
+		affinityMode = "prefer"
+	}
+
+	return &Pool{
+		retryAfterFailure: retryAfterFailure,
+		localZone:         localZone,
+		affinityMode:      affinityMode,
+		spreadTarget:      spreadTarget,
+		spreadCount:       make(map[string]int),
+		zoneNext:          make(map[string]int),
+	}
+}
+
+func (p *Pool) Put(endpoint *Endpoint) {
+	p.Lock()
+	defer p.Unlock()
+
+	for _, e := range p.entries {
+		if e.endpoint.Equal(endpoint) {
+			e.endpoint = endpoint
+			e.updatedAt = time.Now()
+			return
+		}
+	}
+
+	p.entries = append(p.entries, &poolEntry{endpoint: endpoint, updatedAt: time.Now()})
+}
+
+func (p *Pool) Remove(endpoint *Endpoint) bool {
+	p.Lock()
+	defer p.Unlock()
+
+	for i, e := range p.entries {
+		if e.endpoint.Equal(endpoint) {
+			p.entries = append(p.entries[:i], p.entries[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+func (p *Pool) IsEmpty() bool {
+	p.Lock()
+	defer p.Unlock()
+
+	return len(p.entries) == 0
+}
+
+// Filter returns a new Pool, with the same zone-affinity settings as p,
+// containing only the endpoints for which predicate returns true. It is
+// used by the tag router to narrow a route's pool down to endpoints
+// matching a canary/blue-green rule without disturbing p's own rotation.
+func (p *Pool) Filter(predicate func(endpoint *Endpoint) bool) *Pool {
+	p.Lock()
+	defer p.Unlock()
+
+	filtered := NewPool(p.retryAfterFailure, p.localZone, p.affinityMode, p.spreadTarget)
+	for _, e := range p.entries {
+		if predicate(e.endpoint) {
+			filtered.entries = append(filtered.entries, &poolEntry{endpoint: e.endpoint, updatedAt: e.updatedAt})
+		}
+	}
+
+	return filtered
+}
+
+// SyncEntries replaces p's entries with endpoints, leaving p's rotation
+// state (zoneNext/spreadCount) untouched. It lets a long-lived Pool - such
+// as the tag router's cached, narrowed pool for a rule - track its source
+// pool's membership across calls without losing its place in the rotation
+// the way rebuilding the Pool from scratch would.
+func (p *Pool) SyncEntries(endpoints []*Endpoint) {
+	p.Lock()
+	defer p.Unlock()
+
+	entries := make([]*poolEntry, 0, len(endpoints))
+	now := time.Now()
+	for _, e := range endpoints {
+		entries = append(entries, &poolEntry{endpoint: e, updatedAt: now})
+	}
+	p.entries = entries
+}
+
+// Next returns the next endpoint, or nil if the pool has no endpoints. When
+// the pool has a localZone configured, the choice is zone-affine per
+// affinityMode; otherwise endpoints are simply rotated round-robin.
+func (p *Pool) Next() *Endpoint {
+	p.Lock()
+	defer p.Unlock()
+
+	if len(p.entries) == 0 {
+		return nil
+	}
+
+	if p.localZone == "" {
+		return p.nextFrom("", p.entries)
+	}
+
+	switch p.affinityMode {
+	case "strict":
+		return p.nextFrom(p.localZone, p.entriesInZone(p.localZone))
+	case "spread":
+		return p.nextSpread()
+	default: // "prefer"
+		if local := p.entriesInZone(p.localZone); len(local) > 0 {
+			return p.nextFrom(p.localZone, local)
+		}
+		return p.nextFrom("", p.entries)
+	}
+}
+
+// EligibleEndpoints returns the endpoints a zone-aware selection algorithm
+// should choose among, applying the same zone-affinity precedence as Next:
+// "strict" restricts to localZone, "prefer" restricts to localZone when it
+// has any endpoints and otherwise falls back to the whole pool, and "spread"
+// - whose proportional-by-zone weighting isn't expressible as a fixed
+// candidate list - falls back to the whole pool, same as no affinity
+// configured. It's used by LoadBalancer implementations (e.g.
+// least_connections, ip_hash, weighted_round_robin) that pick among
+// candidates themselves rather than delegating to Next.
+func (p *Pool) EligibleEndpoints() []*Endpoint {
+	p.Lock()
+	defer p.Unlock()
+
+	entries := p.entries
+	if p.localZone != "" {
+		switch p.affinityMode {
+		case "strict":
+			entries = p.entriesInZone(p.localZone)
+		case "prefer":
+			if local := p.entriesInZone(p.localZone); len(local) > 0 {
+				entries = local
+			}
+		}
+	}
+
+	out := make([]*Endpoint, len(entries))
+	for i, e := range entries {
+		out[i] = e.endpoint
+	}
+	return out
+}
+
+func (p *Pool) entriesInZone(zone string) []*poolEntry {
+	var out []*poolEntry
+	for _, e := range p.entries {
+		if e.endpoint.Zone == zone {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+// nextFrom round-robins through entries, using a per-key cursor so that
+// picking from different subsets (e.g. "all" vs one zone) doesn't disturb
+// each other's rotation.
+func (p *Pool) nextFrom(key string, entries []*poolEntry) *Endpoint {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	i := p.zoneNext[key] % len(entries)
+	p.zoneNext[key] = i + 1
+
+	return entries[i].endpoint
+}
+
+// nextSpread implements a weighted round-robin across zones, biasing picks
+// toward whichever zone is furthest under its target percentage of the
+// traffic seen so far.
+func (p *Pool) nextSpread() *Endpoint {
+	byZone := make(map[string][]*poolEntry)
+	for _, e := range p.entries {
+		byZone[e.endpoint.Zone] = append(byZone[e.endpoint.Zone], e)
+	}
+
+	total := 0
+	for _, c := range p.spreadCount {
+		total += c
+	}
+
+	var bestZone string
+	bestDeficit := math.Inf(-1)
+	for zone := range byZone {
+		target, ok := p.spreadTarget[zone]
+		if !ok {
+			target = 100 / len(byZone)
+		}
+
+		actual := 0.0
+		if total > 0 {
+			actual = float64(p.spreadCount[zone]) / float64(total) * 100
+		}
+
+		if deficit := float64(target) - actual; deficit > bestDeficit {
+			bestDeficit = deficit
+			bestZone = zone
+		}
+	}
+
+	endpoint := p.nextFrom(bestZone, byZone[bestZone])
+	if endpoint != nil {
+		p.spreadCount[bestZone]++
+	}
+
+	return endpoint
+}
+
+func (p *Pool) Each(f func(endpoint *Endpoint)) {
+	p.Lock()
+	entries := make([]*poolEntry, len(p.entries))
+	copy(entries, p.entries)
+	p.Unlock()
+
+	for _, e := range entries {
+		f(e.endpoint)
+	}
+}
+
+// PruneEndpoints drops entries that haven't been touched within threshold
+// and returns the endpoints that were removed, so callers (e.g. the
+// registry's "prune" lifecycle hook) can report on them.
+func (p *Pool) PruneEndpoints(threshold time.Duration) []*Endpoint {
+	p.Lock()
+	defer p.Unlock()
+
+	cutoff := time.Now().Add(-threshold)
+
+	var pruned []*Endpoint
+	fresh := p.entries[:0]
+	for _, e := range p.entries {
+		if e.updatedAt.After(cutoff) {
+			fresh = append(fresh, e)
+		} else {
+			pruned = append(pruned, e.endpoint)
+		}
+	}
+	p.entries = fresh
+
+	return pruned
+}
+
+func (p *Pool) MarkUpdated(t time.Time) {
+	p.Lock()
+	defer p.Unlock()
+
+	for _, e := range p.entries {
+		e.updatedAt = t
+	}
+}
+
+func (p *Pool) MarshalJSON() ([]byte, error) {
+	p.Lock()
+	defer p.Unlock()
+
+	endpoints := make([]*Endpoint, 0, len(p.entries))
+	for _, e := range p.entries {
+		endpoints = append(endpoints, e.endpoint)
+	}
+
+	return json.Marshal(endpoints)
+}
@@ -0,0 +1,84 @@
+package route_test
+
+import (
+	"time"
+
+	. "github.com/hjinkim-cf1/gorouter/route"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Pool", func() {
+	endpoint := func(zone, id string, port uint16) *Endpoint {
+		return NewEndpoint("app", "10.0.0.1", port, id, zone, nil)
+	}
+
+	Describe("spread zone affinity", func() {
+		It("distributes picks across zones in proportion to the configured targets", func() {
+			pool := NewPool(time.Minute, "z1", "spread", map[string]int{"z1": 70, "z2": 30})
+
+			pool.Put(endpoint("z1", "z1-a", 1))
+			pool.Put(endpoint("z1", "z1-b", 2))
+			pool.Put(endpoint("z2", "z2-a", 3))
+			pool.Put(endpoint("z2", "z2-b", 4))
+
+			counts := map[string]int{}
+			const picks = 1000
+			for i := 0; i < picks; i++ {
+				e := pool.Next()
+				Ω(e).ShouldNot(BeNil())
+				counts[e.Zone]++
+			}
+
+			z1Pct := float64(counts["z1"]) / float64(picks) * 100
+			z2Pct := float64(counts["z2"]) / float64(picks) * 100
+
+			Ω(z1Pct).Should(BeNumerically("~", 70, 5))
+			Ω(z2Pct).Should(BeNumerically("~", 30, 5))
+		})
+
+		It("splits evenly across zones with no explicit target", func() {
+			pool := NewPool(time.Minute, "z1", "spread", nil)
+
+			pool.Put(endpoint("z1", "z1-a", 1))
+			pool.Put(endpoint("z2", "z2-a", 2))
+			pool.Put(endpoint("z3", "z3-a", 3))
+
+			counts := map[string]int{}
+			const picks = 900
+			for i := 0; i < picks; i++ {
+				counts[pool.Next().Zone]++
+			}
+
+			for _, zone := range []string{"z1", "z2", "z3"} {
+				pct := float64(counts[zone]) / float64(picks) * 100
+				Ω(pct).Should(BeNumerically("~", 100.0/3, 5))
+			}
+		})
+
+		It("only picks from zones that have endpoints", func() {
+			pool := NewPool(time.Minute, "z1", "spread", map[string]int{"z1": 50, "z2": 50})
+
+			pool.Put(endpoint("z1", "z1-a", 1))
+
+			for i := 0; i < 10; i++ {
+				e := pool.Next()
+				Ω(e).ShouldNot(BeNil())
+				Ω(e.Zone).Should(Equal("z1"))
+			}
+		})
+	})
+
+	Describe("strict zone affinity", func() {
+		It("never returns an endpoint outside the local zone", func() {
+			pool := NewPool(time.Minute, "z1", "strict", nil)
+
+			pool.Put(endpoint("z1", "z1-a", 1))
+			pool.Put(endpoint("z2", "z2-a", 2))
+
+			for i := 0; i < 10; i++ {
+				Ω(pool.Next().Zone).Should(Equal("z1"))
+			}
+		})
+	})
+})
@@ -0,0 +1,37 @@
+package route
+
+import (
+	"errors"
+	"strings"
+)
+
+var ErrNoWildcard = errors.New("no wildcard available")
+
+// Uri is a route as seen by the registry, e.g. "app.10.244.0.34.xip.io".
+type Uri string
+
+func (u Uri) ToLower() Uri {
+	return Uri(strings.ToLower(string(u)))
+}
+
+func (u Uri) String() string {
+	return string(u)
+}
+
+// NextWildcard strips the leftmost host segment and replaces it with a
+// wildcard, e.g. "foo.bar.com" -> "*.bar.com" -> error. It is used to walk
+// up the route tree when an exact match isn't registered.
+func (u Uri) NextWildcard() (Uri, error) {
+	s := string(u)
+
+	if strings.HasPrefix(s, "*.") {
+		return "", ErrNoWildcard
+	}
+
+	parts := strings.SplitN(s, ".", 2)
+	if len(parts) != 2 {
+		return "", ErrNoWildcard
+	}
+
+	return Uri("*." + parts[1]), nil
+}